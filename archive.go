@@ -0,0 +1,238 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// archiveFormat selects the on-the-fly archive encoding for a directory
+// download.
+type archiveFormat int
+
+const (
+	archiveTarGz archiveFormat = iota
+	archiveZip
+)
+
+var errArchiveTooLarge = errors.New("archive exceeds max-archive-bytes")
+
+// serveArchive streams dir as a compressed archive of the given format
+// directly to w, in place of a directory listing.
+func serveArchive(w http.ResponseWriter, r *http.Request, dir string, format archiveFormat) {
+	name := filepath.Base(dir)
+	lw := &limitWriter{w: w, max: maxArchiveBytes}
+
+	var err error
+	switch format {
+	case archiveTarGz:
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.tar.gz"`)
+		err = writeTarGz(r, lw, dir)
+	case archiveZip:
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.zip"`)
+		err = writeZip(r, lw, dir)
+	}
+	if err != nil {
+		log("fail to archive %s: %s.", dir, err.Error())
+	}
+}
+
+// writeTarGz walks dir and writes a gzip-compressed tar stream to w.
+func writeTarGz(r *http.Request, w io.Writer, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := r.Context().Err(); err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return addSymlinkToTar(tw, path, dir)
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		tw.Close()
+		gz.Close()
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// addSymlinkToTar includes a symlink whose resolved target stays within
+// root as a symlink entry; it skips links that escape root.
+func addSymlinkToTar(tw *tar.Writer, path, dir string) error {
+	target, err := resolveSymlink(path)
+	if err != nil {
+		return nil
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return nil
+	}
+	link, err := os.Readlink(path)
+	if err != nil {
+		return nil
+	}
+	header := &tar.Header{
+		Name:     filepath.ToSlash(rel),
+		Linkname: link,
+		Typeflag: tar.TypeSymlink,
+	}
+	_ = target
+	return tw.WriteHeader(header)
+}
+
+// writeZip walks dir and writes a zip stream to w, including symlinks
+// that stay within root.
+func writeZip(r *http.Request, w io.Writer, dir string) error {
+	zw := zip.NewWriter(w)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := r.Context().Err(); err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return addSymlinkToZip(zw, info, path, dir)
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+		if info.IsDir() {
+			header.Name += "/"
+			_, err := zw.CreateHeader(header)
+			return err
+		}
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(fw, f)
+		return err
+	})
+	if err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// addSymlinkToZip includes a symlink whose resolved target stays within
+// root as a symlink entry, storing the link target as its content; it
+// skips links that escape root.
+func addSymlinkToZip(zw *zip.Writer, info os.FileInfo, path, dir string) error {
+	if _, err := resolveSymlink(path); err != nil {
+		return nil
+	}
+	link, err := os.Readlink(path)
+	if err != nil {
+		return nil
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return nil
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return nil
+	}
+	header.Name = filepath.ToSlash(rel)
+	fw, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write([]byte(link))
+	return err
+}
+
+// resolveSymlink resolves path's target and rejects it if it escapes
+// root.
+func resolveSymlink(path string) (string, error) {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	if target != root && !strings.HasPrefix(target, root+string(filepath.Separator)) {
+		return "", errors.New("symlink escapes root")
+	}
+	return target, nil
+}
+
+// limitWriter aborts once more than max bytes have been written,
+// bounding the resource cost of an archive download.
+type limitWriter struct {
+	w   io.Writer
+	n   int64
+	max int64
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if lw.max > 0 && lw.n+int64(len(p)) > lw.max {
+		return 0, errArchiveTooLarge
+	}
+	n, err := lw.w.Write(p)
+	lw.n += int64(n)
+	return n, err
+}
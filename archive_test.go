@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSymlink(t *testing.T) {
+	dir := t.TempDir()
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatalf("EvalSymlinks(%s): %s", dir, err)
+	}
+	withRoot(t, realDir)
+
+	target := filepath.Join(realDir, "real.txt")
+	if err := os.WriteFile(target, []byte("hi"), 0o644); err != nil {
+		t.Fatalf("write target: %s", err)
+	}
+	insideLink := filepath.Join(realDir, "inside-link")
+	if err := os.Symlink(target, insideLink); err != nil {
+		t.Fatalf("symlink: %s", err)
+	}
+
+	outsideDir := t.TempDir()
+	outsideTarget := filepath.Join(outsideDir, "secret.txt")
+	if err := os.WriteFile(outsideTarget, []byte("secret"), 0o644); err != nil {
+		t.Fatalf("write outside target: %s", err)
+	}
+	outsideLink := filepath.Join(realDir, "outside-link")
+	if err := os.Symlink(outsideTarget, outsideLink); err != nil {
+		t.Fatalf("symlink: %s", err)
+	}
+
+	resolved, err := resolveSymlink(insideLink)
+	if err != nil {
+		t.Fatalf("resolveSymlink(inside-link) error = %s, want nil", err)
+	}
+	if resolved != target {
+		t.Fatalf("resolveSymlink(inside-link) = %q, want %q", resolved, target)
+	}
+
+	if _, err := resolveSymlink(outsideLink); err == nil {
+		t.Fatalf("resolveSymlink(outside-link) = nil error, want rejection of a link escaping root")
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// Backend abstracts the directory/file operations serve needs, so a
+// local disk root and a remote HTTP index can be browsed through the
+// same code path.
+type Backend interface {
+	Stat(path string) (os.FileInfo, error)
+	Open(path string) (io.ReadSeekCloser, error)
+	ReadDir(path string) ([]os.FileInfo, error)
+}
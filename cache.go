@@ -0,0 +1,100 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// statCacheSize bounds the number of stat results kept in memory.
+const statCacheSize = 256
+
+// statInfo is the cached subset of os.FileInfo needed to serve a file.
+type statInfo struct {
+	Size    int64
+	ModTime time.Time
+	ETag    string
+}
+
+// statCache is a small LRU cache of path -> statInfo, used to avoid
+// re-stat'ing the same file (and its .gz/.br siblings) on every hit.
+type statCache struct {
+	mu    sync.Mutex
+	cap   int
+	items map[string]*list.Element
+	order *list.List
+}
+
+type statCacheEntry struct {
+	path string
+	info statInfo
+}
+
+func newStatCache(capacity int) *statCache {
+	return &statCache{
+		cap:   capacity,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+func (c *statCache) get(path string) (statInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[path]
+	if !ok {
+		return statInfo{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*statCacheEntry).info, true
+}
+
+func (c *statCache) put(path string, info statInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[path]; ok {
+		el.Value.(*statCacheEntry).info = info
+		c.order.MoveToFront(el)
+		return
+	}
+	el := c.order.PushFront(&statCacheEntry{path: path, info: info})
+	c.items[path] = el
+	if c.order.Len() > c.cap {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.items, oldest.Value.(*statCacheEntry).path)
+		}
+	}
+}
+
+func (c *statCache) invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[path]; ok {
+		c.order.Remove(el)
+		delete(c.items, path)
+	}
+}
+
+var fileStatCache = newStatCache(statCacheSize)
+
+// statPath stats path, serving from fileStatCache when possible.
+func statPath(path string) (statInfo, bool) {
+	if info, ok := fileStatCache.get(path); ok {
+		return info, true
+	}
+	fi, err := os.Stat(path)
+	if err != nil || fi.IsDir() {
+		return statInfo{}, false
+	}
+	info := statInfo{
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		ETag:    fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size()),
+	}
+	fileStatCache.put(path, info)
+	return info, true
+}
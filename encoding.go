@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// precompressed lists the sibling suffixes we'll transparently serve in
+// place of the original file, in preference order.
+var precompressed = []struct {
+	suffix, encoding string
+}{
+	{".br", "br"},
+	{".gz", "gzip"},
+}
+
+// chooseVariant picks which file to actually serve for query: a
+// precompressed .br/.gz sibling matching the client's Accept-Encoding,
+// or the original file if none exists or the client's Range doesn't fit
+// it (its size differs from the uncompressed original).
+func chooseVariant(query string, r *http.Request) (path string, encoding string, info statInfo) {
+	accept := r.Header.Get("Accept-Encoding")
+	rangeHeader := r.Header.Get("Range")
+	for _, c := range precompressed {
+		if !strings.Contains(accept, c.encoding) {
+			continue
+		}
+		info, ok := statPath(query + c.suffix)
+		if !ok {
+			continue
+		}
+		if !rangeSatisfiable(rangeHeader, info.Size) {
+			continue
+		}
+		return query + c.suffix, c.encoding, info
+	}
+	info, _ = statPath(query)
+	return query, "", info
+}
+
+// rangeSatisfiable reports whether a Range header's byte ranges can be
+// satisfied against a resource of the given size, per RFC 7233: a range
+// with an explicit first-byte-pos is unsatisfiable only if that
+// first-byte-pos is at or past size.
+func rangeSatisfiable(header string, size int64) bool {
+	const prefix = "bytes="
+	if header == "" || !strings.HasPrefix(header, prefix) {
+		return true
+	}
+	for _, part := range strings.Split(strings.TrimPrefix(header, prefix), ",") {
+		part = strings.TrimSpace(part)
+		dash := strings.IndexByte(part, '-')
+		if dash <= 0 {
+			// no explicit start (e.g. a suffix range "-500"), always satisfiable.
+			continue
+		}
+		start, err := strconv.ParseInt(part[:dash], 10, 64)
+		if err != nil {
+			continue
+		}
+		if start >= size {
+			return false
+		}
+	}
+	return true
+}
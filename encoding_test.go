@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChooseVariant(t *testing.T) {
+	dir := t.TempDir()
+	query := filepath.Join(dir, "app.js")
+	writeFile(t, query, "console.log(1)")
+	writeFile(t, query+".gz", "gzipbytes")
+	writeFile(t, query+".br", "brbytes")
+
+	t.Run("prefers br over gzip", func(t *testing.T) {
+		r := newRequest(t, "Accept-Encoding", "gzip, br")
+		path, encoding, _ := chooseVariant(query, r)
+		if path != query+".br" || encoding != "br" {
+			t.Fatalf("chooseVariant() = (%q, %q), want (%q, %q)", path, encoding, query+".br", "br")
+		}
+	})
+
+	t.Run("falls back to gzip when br unavailable", func(t *testing.T) {
+		r := newRequest(t, "Accept-Encoding", "gzip")
+		path, encoding, _ := chooseVariant(query, r)
+		if path != query+".gz" || encoding != "gzip" {
+			t.Fatalf("chooseVariant() = (%q, %q), want (%q, %q)", path, encoding, query+".gz", "gzip")
+		}
+	})
+
+	t.Run("falls back to raw file with no Accept-Encoding", func(t *testing.T) {
+		r := newRequest(t, "", "")
+		path, encoding, _ := chooseVariant(query, r)
+		if path != query || encoding != "" {
+			t.Fatalf("chooseVariant() = (%q, %q), want (%q, %q)", path, encoding, query, "")
+		}
+	})
+
+	t.Run("falls back to raw file when Range exceeds the encoded variant", func(t *testing.T) {
+		r := newRequest(t, "Accept-Encoding", "br")
+		r.Header.Set("Range", "bytes=1000-2000")
+		path, encoding, _ := chooseVariant(query, r)
+		if path != query || encoding != "" {
+			t.Fatalf("chooseVariant() = (%q, %q), want raw file fallback (%q, %q)", path, encoding, query, "")
+		}
+	})
+
+	t.Run("serves encoded variant when Range fits it", func(t *testing.T) {
+		r := newRequest(t, "Accept-Encoding", "br")
+		r.Header.Set("Range", "bytes=0-3")
+		path, encoding, _ := chooseVariant(query, r)
+		if path != query+".br" || encoding != "br" {
+			t.Fatalf("chooseVariant() = (%q, %q), want (%q, %q)", path, encoding, query+".br", "br")
+		}
+	})
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %s", path, err)
+	}
+}
+
+func newRequest(t *testing.T, header, value string) *http.Request {
+	t.Helper()
+	r, err := http.NewRequest(http.MethodGet, "/app.js", nil)
+	if err != nil {
+		t.Fatalf("new request: %s", err)
+	}
+	if header != "" {
+		r.Header.Set(header, value)
+	}
+	return r
+}
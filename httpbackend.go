@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// HTTPBackend serves a remote HTTP directory-index server as if it were
+// a local tree, by scraping its generated listing pages and issuing
+// Range GETs for file content. This lets the tool act as a caching
+// browser for other index-listing servers.
+type HTTPBackend struct {
+	Remote string
+}
+
+func (b HTTPBackend) urlFor(p string) string {
+	return strings.TrimRight(b.Remote, "/") + path.Clean("/"+p)
+}
+
+func (b HTTPBackend) Stat(p string) (os.FileInfo, error) {
+	if p == "" || p == "/" {
+		return httpFileInfo{name: "/", isDir: true}, nil
+	}
+	if strings.HasSuffix(p, "/") {
+		// Directories are only ever reached via a /-suffixed link, per
+		// the convention our own listing template uses; confirm it
+		// exists by fetching its index page.
+		if _, err := b.ReadDir(p); err != nil {
+			return nil, err
+		}
+		return httpFileInfo{name: path.Base(strings.TrimSuffix(p, "/")), isDir: true}, nil
+	}
+
+	resp, err := http.Head(b.urlFor(p))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, os.ErrNotExist
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("remote returned %s", resp.Status)
+	}
+	size, _ := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	return httpFileInfo{name: path.Base(p), size: size}, nil
+}
+
+func (b HTTPBackend) ReadDir(p string) ([]os.FileInfo, error) {
+	resp, err := http.Get(b.urlFor(p))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, os.ErrNotExist
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("remote returned %s", resp.Status)
+	}
+	if !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return nil, errors.New("not a directory index")
+	}
+
+	links, err := parseIndexLinks(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]os.FileInfo, 0, len(links))
+	for _, link := range links {
+		out = append(out, httpFileInfo{
+			name:  strings.TrimSuffix(link, "/"),
+			isDir: strings.HasSuffix(link, "/"),
+		})
+	}
+	return out, nil
+}
+
+func (b HTTPBackend) Open(p string) (io.ReadSeekCloser, error) {
+	info, err := b.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	return &httpFile{backend: b, path: p, size: info.Size()}, nil
+}
+
+// parseIndexLinks extracts hrefs from an HTML directory index page,
+// following <a href> links and filtering out "../" and query-only
+// links.
+func parseIndexLinks(r io.Reader) ([]string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			for _, attr := range n.Attr {
+				if attr.Key != "href" {
+					continue
+				}
+				if isSkippableIndexLink(attr.Val) {
+					continue
+				}
+				links = append(links, attr.Val)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return links, nil
+}
+
+func isSkippableIndexLink(href string) bool {
+	if href == "" || href == "../" || href == ".." {
+		return true
+	}
+	if strings.HasPrefix(href, "?") || strings.HasPrefix(href, "#") {
+		return true
+	}
+	u, err := url.Parse(href)
+	return err != nil || u.IsAbs()
+}
+
+// httpFileInfo is the os.FileInfo implementation returned by
+// HTTPBackend.
+type httpFileInfo struct {
+	name  string
+	size  int64
+	isDir bool
+}
+
+func (fi httpFileInfo) Name() string { return fi.name }
+func (fi httpFileInfo) Size() int64  { return fi.size }
+func (fi httpFileInfo) Mode() os.FileMode {
+	if fi.isDir {
+		return os.ModeDir
+	}
+	return 0
+}
+func (fi httpFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi httpFileInfo) IsDir() bool        { return fi.isDir }
+func (fi httpFileInfo) Sys() interface{}   { return nil }
+
+// httpFile implements io.ReadSeekCloser over a remote file by issuing
+// Range GET requests, so it can be passed directly to
+// http.ServeContent.
+type httpFile struct {
+	backend HTTPBackend
+	path    string
+	size    int64
+	offset  int64
+}
+
+func (f *httpFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		f.offset = offset
+	case io.SeekCurrent:
+		f.offset += offset
+	case io.SeekEnd:
+		f.offset = f.size + offset
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	return f.offset, nil
+}
+
+func (f *httpFile) Read(p []byte) (int, error) {
+	if f.offset >= f.size {
+		return 0, io.EOF
+	}
+	end := f.offset + int64(len(p)) - 1
+	if end >= f.size {
+		end = f.size - 1
+	}
+
+	req, err := http.NewRequest(http.MethodGet, f.backend.urlFor(f.path), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", f.offset, end))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("remote returned %s", resp.Status)
+	}
+
+	n, err := io.ReadFull(resp.Body, p[:end-f.offset+1])
+	f.offset += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *httpFile) Close() error { return nil }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseIndexLinks(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html>
+<body>
+<a href="../">../</a>
+<a href="?sort=name">sort</a>
+<a href="#top">top</a>
+<a href="subdir/">subdir/</a>
+<a href="notes.txt">notes.txt</a>
+<a href="https://example.com/elsewhere">elsewhere</a>
+</body>
+</html>`
+
+	links, err := parseIndexLinks(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("parseIndexLinks() error = %s", err)
+	}
+
+	sort.Strings(links)
+	want := []string{"notes.txt", "subdir/"}
+	if !reflect.DeepEqual(links, want) {
+		t.Fatalf("parseIndexLinks() = %v, want %v", links, want)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileEntry describes a single row in a directory listing.
+type fileEntry struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// sortEntries orders entries with directories first, then
+// case-insensitively by name. The synthetic ".." entry, when present,
+// always sorts first.
+func sortEntries(entries []fileEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Name == ".." {
+			return true
+		}
+		if b.Name == ".." {
+			return false
+		}
+		if a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+}
+
+// listingRow is the data passed to listingTemplate for a single entry.
+type listingRow struct {
+	Name    string
+	Href    string
+	Size    string
+	ModTime string
+	IsDir   bool
+}
+
+// listingJSON is the shape of an entry in the JSON listing response.
+type listingJSON struct {
+	Name  string    `json:"name"`
+	Size  int64     `json:"size"`
+	MTime time.Time `json:"mtime"`
+	IsDir bool      `json:"is_dir"`
+}
+
+var listingTemplate = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>{{.Path}}</title>
+</head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<hr>
+<table>
+<tr><th>Name</th><th>Size</th><th>Last Modified</th></tr>
+{{range .Rows}}<tr><td><a href="{{.Href}}">{{.Name}}</a></td><td>{{.Size}}</td><td>{{.ModTime}}</td></tr>
+{{end}}</table>
+{{if .UploadForm}}<hr>
+<form method="POST" enctype="multipart/form-data">
+<input type="file" name="file">
+<input type="submit" value="Upload">
+</form>
+{{end}}</body>
+</html>`))
+
+// wantsJSON reports whether the request asked for a JSON listing, via
+// Accept: application/json or ?format=json.
+func wantsJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "json" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+func listFiles(w http.ResponseWriter, r *http.Request, entries []fileEntry) error {
+	if wantsJSON(r) {
+		return listFilesJSON(w, entries)
+	}
+	return listFilesHTML(w, r, entries)
+}
+
+func listFilesJSON(w http.ResponseWriter, entries []fileEntry) error {
+	out := make([]listingJSON, 0, len(entries))
+	for _, e := range entries {
+		if e.Name == ".." {
+			continue
+		}
+		out = append(out, listingJSON{Name: e.Name, Size: e.Size, MTime: e.ModTime, IsDir: e.IsDir})
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(out)
+}
+
+func listFilesHTML(w http.ResponseWriter, r *http.Request, entries []fileEntry) error {
+	rows := make([]listingRow, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name
+		href := path.Join(r.URL.Path, url.PathEscape(e.Name))
+		size := "-"
+		if !e.IsDir {
+			size = strconv.FormatInt(e.Size, 10)
+		}
+		if e.IsDir {
+			name += "/"
+			href += "/"
+		}
+		rows = append(rows, listingRow{
+			Name:    name,
+			Href:    href,
+			Size:    size,
+			ModTime: e.ModTime.Format("2006-01-02 15:04:05"),
+			IsDir:   e.IsDir,
+		})
+	}
+	data := struct {
+		Path       string
+		Rows       []listingRow
+		UploadForm bool
+	}{
+		Path:       r.URL.Path,
+		Rows:       rows,
+		UploadForm: writable,
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	return listingTemplate.Execute(w, data)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSortEntries(t *testing.T) {
+	entries := []fileEntry{
+		{Name: "banana.txt"},
+		{Name: "Apple", IsDir: true},
+		{Name: "..", IsDir: true},
+		{Name: "zebra", IsDir: true},
+		{Name: "apricot.txt"},
+		{Name: "Banana.txt"},
+	}
+	sortEntries(entries)
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name)
+	}
+	want := []string{"..", "Apple", "zebra", "apricot.txt", "banana.txt", "Banana.txt"}
+	if len(got) != len(want) {
+		t.Fatalf("sortEntries() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sortEntries() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSortEntriesStableForEqualKeys(t *testing.T) {
+	t0 := time.Now()
+	entries := []fileEntry{
+		{Name: "dup", ModTime: t0},
+		{Name: "dup", ModTime: t0.Add(time.Second)},
+	}
+	sortEntries(entries)
+	if entries[0].ModTime != t0 {
+		t.Fatalf("sortEntries() did not preserve input order for equal keys")
+	}
+}
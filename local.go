@@ -0,0 +1,23 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// LocalBackend serves files from the local disk rooted at root.
+type LocalBackend struct{}
+
+func (LocalBackend) Stat(path string) (os.FileInfo, error) {
+	return os.Stat(filepath.Join(root, path))
+}
+
+func (LocalBackend) Open(path string) (io.ReadSeekCloser, error) {
+	return os.Open(filepath.Join(root, path))
+}
+
+func (LocalBackend) ReadDir(path string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(filepath.Join(root, path))
+}
@@ -1,13 +1,13 @@
 package main
 
 import (
-	"bytes"
+	"crypto/tls"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -34,23 +34,56 @@ func (w *ResponseWriter) Write(b []byte) (int, error) {
 }
 
 var (
-	root  string
-	file  string
-	netIF string
-	port  int
+	root            string
+	file            string
+	netIF           string
+	port            int
+	writable        bool
+	maxUpload       int64
+	token           string
+	maxArchiveBytes int64
+	backendKind     string
+	remote          string
+	backend         Backend
+	tlsCert         string
+	tlsKey          string
+	tlsAuto         bool
+	httpRedirect    int
 )
 
 func init() {
 	flag.StringVar(&root, "f", "", "root directory or a file allowed to be visited.")
 	flag.IntVar(&port, "p", 8000, "http server port")
 	flag.StringVar(&netIF, "i", "", "net interface")
-	flag.Parse()
+	flag.BoolVar(&writable, "w", false, "enable write mode, allowing file uploads.")
+	flag.Int64Var(&maxUpload, "max-upload", 32<<20, "max size in bytes of an uploaded file.")
+	flag.StringVar(&token, "token", "", "shared token required to upload files, via Authorization: Bearer or ?token=.")
+	flag.Int64Var(&maxArchiveBytes, "max-archive-bytes", 1<<30, "max total bytes streamed for an on-the-fly directory archive.")
+	flag.StringVar(&backendKind, "backend", "local", "filesystem backend to serve: local|http.")
+	flag.StringVar(&remote, "remote", "", "remote root URL to browse, when -backend=http.")
+	flag.StringVar(&tlsCert, "tls-cert", "", "TLS certificate file; serves HTTPS when set.")
+	flag.StringVar(&tlsKey, "tls-key", "", "TLS private key file; serves HTTPS when set.")
+	flag.BoolVar(&tlsAuto, "tls-auto", false, "generate an in-memory self-signed certificate and serve HTTPS.")
+	flag.IntVar(&httpRedirect, "http-redirect", 0, "port for a plain HTTP listener that 301s to HTTPS; 0 disables it.")
 }
 
 func main() {
-	err := resolvePath()
-	if err != nil {
-		exit(err)
+	flag.Parse()
+
+	switch backendKind {
+	case "http":
+		if remote == "" {
+			exit("-remote is required when -backend=http.")
+		}
+		file = "*"
+		backend = HTTPBackend{Remote: remote}
+	case "local":
+		if err := resolvePath(); err != nil {
+			exit(err)
+		}
+		backend = LocalBackend{}
+	default:
+		exit("unknown -backend: " + backendKind)
 	}
 
 	ip, err := resolveInterface()
@@ -70,8 +103,29 @@ func main() {
 		serve(pw, r)
 	})
 	addr := ip + ":" + strconv.Itoa(port)
-	log("serveing on http://%s.", addr)
-	err = http.ListenAndServe(addr, nil)
+	srv := &http.Server{Addr: addr}
+
+	if tlsCert == "" && tlsKey == "" && !tlsAuto {
+		log("serveing on http://%s.", addr)
+		err = srv.ListenAndServe()
+		if err != nil {
+			exit(err)
+		}
+		return
+	}
+
+	cert, err := loadOrGenerateCert(tlsCert, tlsKey, tlsAuto, ip)
+	if err != nil {
+		exit(err)
+	}
+	srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	if httpRedirect > 0 {
+		go serveHTTPRedirect(ip, httpRedirect, port)
+	}
+
+	log("serveing on https://%s.", addr)
+	err = srv.ListenAndServeTLS("", "")
 	if err != nil {
 		exit(err)
 	}
@@ -86,49 +140,102 @@ func serve(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	query := filepath.Join(root, r.URL.Path)
-	fi, err := os.Stat(query)
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		upload(w, r)
+		return
+	}
+
+	fi, err := backend.Stat(r.URL.Path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			http.NotFound(w, r)
 			return
 		}
-		log("fail to stat file %s: %s.", query, err.Error())
+		log("fail to stat %s: %s.", r.URL.Path, err.Error())
 		internalServerError(w)
 		return
 	}
+	_, isLocal := backend.(LocalBackend)
+
 	if fi.IsDir() {
-		var files []string
+		if isLocal {
+			query := filepath.Join(root, r.URL.Path)
+			switch r.URL.Query().Get("archive") {
+			case "tar.gz":
+				serveArchive(w, r, query, archiveTarGz)
+				return
+			case "zip":
+				serveArchive(w, r, query, archiveZip)
+				return
+			}
+		}
+
+		var entries []fileEntry
 		if file == "*" {
 			if r.URL.Path != "/" {
-				files = append(files, "..")
+				entries = append(entries, fileEntry{Name: "..", IsDir: true})
 			}
-			fis, err := ioutil.ReadDir(query)
+			fis, err := backend.ReadDir(r.URL.Path)
 			if err != nil {
 				internalServerError(w)
 				return
 			}
 			for _, fi := range fis {
-				files = append(files, fi.Name())
+				entries = append(entries, fileEntry{
+					Name:    fi.Name(),
+					Size:    fi.Size(),
+					ModTime: fi.ModTime(),
+					IsDir:   fi.IsDir(),
+				})
 			}
 		} else {
-			files = append(files, file)
+			ffi, err := backend.Stat(file)
+			if err != nil {
+				internalServerError(w)
+				return
+			}
+			entries = append(entries, fileEntry{
+				Name:    file,
+				Size:    ffi.Size(),
+				ModTime: ffi.ModTime(),
+				IsDir:   ffi.IsDir(),
+			})
 		}
-		err := listFiles(w, r, files)
+		sortEntries(entries)
+		err := listFiles(w, r, entries)
 		if err != nil {
-			log("fail to list files %s: %s.", query, err.Error())
+			log("fail to list files %s: %s.", r.URL.Path, err.Error())
+		}
+	} else if isLocal {
+		query := filepath.Join(root, r.URL.Path)
+		variant, encoding, info := chooseVariant(query, r)
+		f, err := os.Open(variant)
+		if err != nil {
+			log("fail to open file %s: %s.", variant, err.Error())
+			internalServerError(w)
+			return
+		}
+		if encoding != "" {
+			w.Header().Set("Content-Encoding", encoding)
+			w.Header().Set("Vary", "Accept-Encoding")
+		}
+		w.Header().Set("ETag", info.ETag)
+		http.ServeContent(w, r, filepath.Base(query), info.ModTime, f)
+		err = f.Close()
+		if err != nil {
+			log("fail to close %s: %s.", variant, err.Error())
 		}
 	} else {
-		f, err := os.Open(query)
+		f, err := backend.Open(r.URL.Path)
 		if err != nil {
-			log("fail to open file %s: %s.", query, err.Error())
+			log("fail to open %s: %s.", r.URL.Path, err.Error())
 			internalServerError(w)
 			return
 		}
-		http.ServeContent(w, r, "foo", fi.ModTime(), f)
+		http.ServeContent(w, r, path.Base(r.URL.Path), fi.ModTime(), f)
 		err = f.Close()
 		if err != nil {
-			log("fail to close %s: %s.", query, err.Error())
+			log("fail to close %s: %s.", r.URL.Path, err.Error())
 		}
 	}
 }
@@ -203,25 +310,3 @@ func log(format string, a ...interface{}) {
 func internalServerError(w http.ResponseWriter) {
 	http.Error(w, "internal server error.", 500)
 }
-
-func listFiles(w http.ResponseWriter, r *http.Request, files []string) error {
-	html := `<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <title>%s</title>
-</head>
-<body>
-<h1>Index of %s</h1>
-<hr>
-<p>
-%s</p>
-</body>
-</html>`
-	buf := new(bytes.Buffer)
-	for _, file := range files {
-		buf.WriteString(fmt.Sprintf("<a href=\"%s\">%s</a><br>\n", filepath.Join(r.URL.Path, file), file))
-	}
-	_, err := w.Write([]byte(fmt.Sprintf(html, r.URL.Path, r.URL.Path, buf.String())))
-	return err
-}
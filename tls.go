@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadOrGenerateCert returns the TLS certificate to serve: the
+// configured cert/key pair, or an in-memory self-signed one when
+// autoGen is set and no cert/key was provided.
+func loadOrGenerateCert(certFile, keyFile string, autoGen bool, host string) (tls.Certificate, error) {
+	if certFile != "" && keyFile != "" {
+		return tls.LoadX509KeyPair(certFile, keyFile)
+	}
+	if certFile != "" || keyFile != "" {
+		return tls.Certificate{}, errors.New("-tls-cert and -tls-key must both be set")
+	}
+	if !autoGen {
+		return tls.Certificate{}, errors.New("no TLS certificate configured; set -tls-cert/-tls-key or -tls-auto")
+	}
+
+	cert, der, err := generateSelfSignedCert(host)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	log("self-signed certificate fingerprint (sha256): %s.", fingerprint(der))
+	return cert, nil
+}
+
+// generateSelfSignedCert creates an in-memory ECDSA self-signed
+// certificate covering host and "localhost", valid for one year.
+func generateSelfSignedCert(host string) (tls.Certificate, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: host},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else if host != "" {
+		template.DNSNames = append(template.DNSNames, host)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	return cert, der, nil
+}
+
+// fingerprint formats the SHA-256 fingerprint of a certificate's DER
+// bytes as colon-separated uppercase hex, so users can verify it
+// out-of-band.
+func fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	hex := strings.ToUpper(strings.ReplaceAll(fmt.Sprintf("% x", sum), " ", ":"))
+	return hex
+}
+
+// serveHTTPRedirect runs a plain HTTP listener on redirectPort that
+// 301s every request to the HTTPS listener on tlsPort.
+func serveHTTPRedirect(host string, redirectPort, tlsPort int) {
+	addr := host + ":" + strconv.Itoa(redirectPort)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := "https://" + host + ":" + strconv.Itoa(tlsPort) + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+	log("redirecting http://%s to https.", addr)
+	if err := http.ListenAndServe(addr, handler); err != nil {
+		log("http redirect listener failed: %s.", err.Error())
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/x509"
+	"strings"
+	"testing"
+)
+
+func TestGenerateSelfSignedCert(t *testing.T) {
+	cert, der, err := generateSelfSignedCert("192.0.2.1")
+	if err != nil {
+		t.Fatalf("generateSelfSignedCert() error = %s", err)
+	}
+	if len(cert.Certificate) == 0 {
+		t.Fatalf("generateSelfSignedCert() returned an empty tls.Certificate")
+	}
+
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() error = %s", err)
+	}
+
+	if err := parsed.VerifyHostname("192.0.2.1"); err != nil {
+		t.Fatalf("cert does not cover host %q: %s", "192.0.2.1", err)
+	}
+	if err := parsed.VerifyHostname("localhost"); err != nil {
+		t.Fatalf("cert does not cover %q: %s", "localhost", err)
+	}
+
+	fp1 := fingerprint(der)
+	fp2 := fingerprint(der)
+	if fp1 != fp2 {
+		t.Fatalf("fingerprint() not stable: %q != %q", fp1, fp2)
+	}
+	if !strings.Contains(fp1, ":") {
+		t.Fatalf("fingerprint() = %q, want colon-separated hex", fp1)
+	}
+}
+
+func TestLoadOrGenerateCertRequiresCertAndKeyTogether(t *testing.T) {
+	if _, err := loadOrGenerateCert("cert.pem", "", false, "localhost"); err == nil {
+		t.Fatalf("loadOrGenerateCert() with only -tls-cert set = nil error, want rejection")
+	}
+}
+
+func TestLoadOrGenerateCertRequiresAutoOrFiles(t *testing.T) {
+	if _, err := loadOrGenerateCert("", "", false, "localhost"); err == nil {
+		t.Fatalf("loadOrGenerateCert() with nothing configured = nil error, want rejection")
+	}
+}
+
+func TestLoadOrGenerateCertAuto(t *testing.T) {
+	cert, err := loadOrGenerateCert("", "", true, "localhost")
+	if err != nil {
+		t.Fatalf("loadOrGenerateCert() error = %s", err)
+	}
+	if len(cert.Certificate) == 0 {
+		t.Fatalf("loadOrGenerateCert() returned an empty tls.Certificate")
+	}
+}
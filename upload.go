@@ -0,0 +1,119 @@
+package main
+
+import (
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// upload handles POST/PUT requests against a directory and writes the
+// submitted multipart/form-data files into the corresponding place on
+// disk.
+func upload(w http.ResponseWriter, r *http.Request) {
+	if !writable {
+		http.Error(w, "write mode is disabled.", http.StatusForbidden)
+		return
+	}
+	if _, isLocal := backend.(LocalBackend); !isLocal {
+		http.Error(w, "uploads are only supported on the local backend.", http.StatusForbidden)
+		return
+	}
+	if file != "*" {
+		http.Error(w, "uploads are disabled in single-file mode.", http.StatusForbidden)
+		return
+	}
+	if !checkToken(r) {
+		http.Error(w, "invalid or missing token.", http.StatusUnauthorized)
+		return
+	}
+
+	dir, err := destDir(r.URL.Path)
+	if err != nil {
+		http.Error(w, "invalid path.", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUpload)
+	err = r.ParseMultipartForm(maxUpload)
+	if err != nil {
+		log("fail to parse multipart form: %s.", err.Error())
+		http.Error(w, "upload too large or invalid.", http.StatusBadRequest)
+		return
+	}
+	if r.MultipartForm == nil {
+		http.Error(w, "no files uploaded.", http.StatusBadRequest)
+		return
+	}
+
+	for _, headers := range r.MultipartForm.File {
+		for _, header := range headers {
+			if err := saveUpload(dir, header); err != nil {
+				log("fail to save upload %s: %s.", header.Filename, err.Error())
+				internalServerError(w)
+				return
+			}
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// saveUpload copies a single multipart file part into dir, rejecting
+// path traversal in the submitted filename.
+func saveUpload(dir string, header *multipart.FileHeader) error {
+	name := filepath.Base(header.Filename)
+	if name == "." || name == ".." || name == "" {
+		return os.ErrInvalid
+	}
+	dest := filepath.Join(dir, name)
+
+	src, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	fileStatCache.invalidate(dest)
+	return err
+}
+
+// destDir resolves the directory an upload targets, rejecting any path
+// that would escape root.
+func destDir(urlPath string) (string, error) {
+	if root == "" {
+		return "", os.ErrInvalid
+	}
+	dir := filepath.Join(root, urlPath)
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if dir != root && !strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		return "", os.ErrInvalid
+	}
+	return dir, nil
+}
+
+// checkToken reports whether the request carries the configured shared
+// token, via an Authorization: Bearer header or a ?token= query param.
+// When no token is configured, every request passes.
+func checkToken(r *http.Request) bool {
+	if token == "" {
+		return true
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		if strings.TrimPrefix(auth, "Bearer ") == token {
+			return true
+		}
+	}
+	return r.URL.Query().Get("token") == token
+}
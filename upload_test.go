@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func withRoot(t *testing.T, dir string) {
+	t.Helper()
+	prevRoot, prevFile := root, file
+	root, file = dir, "*"
+	t.Cleanup(func() { root, file = prevRoot, prevFile })
+}
+
+func TestDestDir(t *testing.T) {
+	dir := t.TempDir()
+	withRoot(t, dir)
+
+	if got, err := destDir("/"); err != nil || got != dir {
+		t.Fatalf("destDir(/) = (%q, %v), want (%q, nil)", got, err, dir)
+	}
+
+	if got, err := destDir("/sub"); err != nil || got != filepath.Join(dir, "sub") {
+		t.Fatalf("destDir(/sub) = (%q, %v), want (%q, nil)", got, err, filepath.Join(dir, "sub"))
+	}
+
+	if _, err := destDir("/../../etc"); err == nil {
+		t.Fatalf("destDir(/../../etc) = nil error, want path-traversal rejection")
+	}
+}
+
+// TestDestDirRejectsEmptyRoot guards against the case where root is
+// unset (as it is when -backend=http, which never calls resolvePath):
+// the traversal check `!strings.HasPrefix(dir, root+separator)`
+// degenerates to "is dir absolute", which is true for every path.
+func TestDestDirRejectsEmptyRoot(t *testing.T) {
+	withRoot(t, "")
+
+	if _, err := destDir("/../../../etc/cron.d/evil"); err == nil {
+		t.Fatalf("destDir() with empty root = nil error, want rejection")
+	}
+}
+
+// TestUploadRejectsNonLocalBackend guards against uploads being
+// accepted for the HTTP backend, whose destDir has no sandboxing root
+// to enforce (see TestDestDirRejectsEmptyRoot).
+func TestUploadRejectsNonLocalBackend(t *testing.T) {
+	prevWritable, prevBackend := writable, backend
+	writable, backend = true, HTTPBackend{Remote: "http://example.invalid"}
+	t.Cleanup(func() { writable, backend = prevWritable, prevBackend })
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", "evil.txt")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %s", err)
+	}
+	part.Write([]byte("payload"))
+	w.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	upload(rec, r)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("upload() with non-local backend = status %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+// fileHeaderWithFilename builds a real *multipart.FileHeader (with a
+// working Open()) carrying the given filename, by round-tripping it
+// through an actual multipart form.
+func fileHeaderWithFilename(t *testing.T, filename, content string) *multipart.FileHeader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("CreateFormFile: %s", err)
+	}
+	if _, err := part.Write([]byte(content)); err != nil {
+		t.Fatalf("write part: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close writer: %s", err)
+	}
+
+	r := multipart.NewReader(&buf, w.Boundary())
+	form, err := r.ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("ReadForm: %s", err)
+	}
+	return form.File["file"][0]
+}
+
+func TestSaveUploadRejectsTraversalInFilename(t *testing.T) {
+	dir := t.TempDir()
+	withRoot(t, dir)
+
+	header := fileHeaderWithFilename(t, "../../escaped.txt", "payload")
+	if err := saveUpload(dir, header); err != nil {
+		t.Fatalf("saveUpload() = %v, want nil (traversal should be sanitized, not surfaced as an error)", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(filepath.Dir(filepath.Dir(dir)), "escaped.txt")); err == nil {
+		t.Fatalf("saveUpload() wrote outside dir despite a traversal filename")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped.txt")); err != nil {
+		t.Fatalf("saveUpload() did not write the sanitized filename inside dir: %s", err)
+	}
+}
+
+func TestSaveUploadRejectsDotFilename(t *testing.T) {
+	dir := t.TempDir()
+	withRoot(t, dir)
+
+	header := fileHeaderWithFilename(t, "..", "payload")
+	if err := saveUpload(dir, header); err == nil {
+		t.Fatalf("saveUpload() with filename \"..\" = nil error, want rejection")
+	}
+}